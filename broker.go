@@ -0,0 +1,219 @@
+package goku
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// scheduledSuffix is appended to a queue's name to get the sorted set that
+// RunAt, and a WorkerPool's RetryPolicy, schedule delayed jobs on.
+const scheduledSuffix = ":scheduled"
+
+// promoteScheduledScript atomically moves every member of a queue's
+// :scheduled set due by now onto the queue itself. Running the whole
+// check-and-move as one script, rather than ZRANGEBYSCORE followed by a
+// separate ZREM/LPUSH pipeline, is what lets a Broker's own scheduler and
+// a WorkerPool's retry scheduler poll the very same key without either
+// one double-promoting a payload the other already claimed.
+var promoteScheduledScript = redis.NewScript(2, `
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+for _, payload in ipairs(due) do
+	redis.call('ZREM', KEYS[1], payload)
+	redis.call('LPUSH', KEYS[2], payload)
+end
+return due
+`)
+
+// promoteDueScheduled moves queue's due scheduled payloads onto queue
+// itself, returning the ones it promoted.
+func promoteDueScheduled(conn redis.Conn, queue string, now time.Time) ([]string, error) {
+	key := hashTagKey(queue, scheduledSuffix)
+	return redis.Strings(promoteScheduledScript.Do(conn, key, queue, now.Unix()))
+}
+
+// BrokerConfig configures a Broker.
+type BrokerConfig struct {
+	// Hostport is the address of a single Redis instance backing the
+	// queue. Ignored if SentinelAddrs, ClusterAddrs, or Pool is set.
+	Hostport string
+
+	// SentinelAddrs and MasterName, if set, connect the Broker through
+	// Redis Sentinel instead of a fixed Hostport.
+	SentinelAddrs []string
+	MasterName    string
+
+	// ClusterAddrs, if set, connects the Broker to a Redis Cluster seeded
+	// from these addresses instead of a single instance.
+	ClusterAddrs []string
+
+	// Pool, if set, overrides goku's own pool construction from the
+	// fields above entirely.
+	Pool RedisPool
+
+	// Timeout bounds how long Redis operations are allowed to take.
+	Timeout time.Duration
+
+	// DefaultQueue is the queue Run and RunAt enqueue jobs onto.
+	DefaultQueue string
+
+	// StatusHook, if set, is notified with a queued event each time the
+	// Broker puts a job onto a queue. The Broker reports no other states;
+	// running and later transitions go to WorkerPoolOptions.StatusHook.
+	StatusHook StatusHook
+
+	// Codec marshals a job's own fields for storage in its envelope.
+	// Defaults to JSONCodec. Every registered codec remains available to
+	// unmarshal regardless of this setting, so a queue can be migrated to
+	// a new codec without disturbing jobs already enqueued under the old
+	// one.
+	Codec Codec
+}
+
+func (c BrokerConfig) redisConfig() redisConfig {
+	return redisConfig{
+		Hostport:      c.Hostport,
+		SentinelAddrs: c.SentinelAddrs,
+		MasterName:    c.MasterName,
+		ClusterAddrs:  c.ClusterAddrs,
+		Pool:          c.Pool,
+	}
+}
+
+// Broker enqueues jobs onto Redis-backed queues for a WorkerPool to pick up.
+type Broker struct {
+	config BrokerConfig
+	pool   RedisPool
+	codec  Codec
+
+	// instanceID identifies this Broker process when competing for
+	// periodic-job leadership against other Broker processes.
+	instanceID string
+
+	periodicMu sync.RWMutex
+	periodic   map[string]*periodicEntry
+}
+
+// envelope is the wire format a job is marshaled to before being pushed
+// onto a queue: its registered Name alongside its own fields, encoded as
+// Payload by the codec named in Codec.
+type envelope struct {
+	ID      string `json:"ID"`
+	N       string `json:"N"`
+	Codec   string `json:"Codec"`
+	Payload []byte `json:"Payload"`
+	Attempt int    `json:"Attempt,omitempty"`
+	Unique  string `json:"Unique,omitempty"`
+}
+
+// NewBroker creates a Broker from config, validating required fields and
+// starting the background goroutine that promotes due RunAt jobs.
+func NewBroker(config BrokerConfig) (*Broker, error) {
+	if config.DefaultQueue == "" {
+		return nil, errMissingQueue
+	}
+
+	pool, err := newPool(config.redisConfig(), config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	codec := config.Codec
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	broker := &Broker{
+		config:     config,
+		pool:       pool,
+		codec:      codec,
+		instanceID: newInstanceID(),
+		periodic:   make(map[string]*periodicEntry),
+	}
+
+	go broker.runScheduler()
+	go broker.runPeriodicScheduler()
+
+	return broker, nil
+}
+
+// Run enqueues job onto the broker's DefaultQueue for immediate execution.
+func (b *Broker) Run(job Job) error {
+	if reflect.ValueOf(job).Kind() == reflect.Ptr {
+		return ErrPointer
+	}
+
+	payload, id, err := marshalJob(job, "", b.codec)
+	if err != nil {
+		return err
+	}
+
+	conn := b.pool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("LPUSH", b.config.DefaultQueue, payload); err != nil {
+		return err
+	}
+
+	emitStatus(b.pool, b.config.StatusHook, id, job.Name(), StatusQueued)
+	return nil
+}
+
+// RunAt schedules job to be enqueued onto the broker's DefaultQueue at t.
+func (b *Broker) RunAt(job Job, t time.Time) error {
+	if reflect.ValueOf(job).Kind() == reflect.Ptr {
+		return ErrPointer
+	}
+
+	payload, _, err := marshalJob(job, "", b.codec)
+	if err != nil {
+		return err
+	}
+
+	conn := b.pool.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("ZADD", hashTagKey(b.config.DefaultQueue, scheduledSuffix), t.Unix(), payload)
+	return err
+}
+
+// runScheduler periodically promotes due RunAt jobs onto DefaultQueue.
+func (b *Broker) runScheduler() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		b.promoteDueJobs()
+	}
+}
+
+func (b *Broker) promoteDueJobs() {
+	conn := b.pool.Get()
+	defer conn.Close()
+
+	due, err := promoteDueScheduled(conn, b.config.DefaultQueue, time.Now())
+	if err != nil || len(due) == 0 {
+		return
+	}
+
+	for _, payload := range due {
+		var env envelope
+		if err := json.Unmarshal([]byte(payload), &env); err == nil {
+			emitStatus(b.pool, b.config.StatusHook, env.ID, env.N, StatusQueued)
+		}
+	}
+}
+
+func marshalJob(job Job, uniqueKey string, codec Codec) ([]byte, string, error) {
+	data, err := codec.Marshal(job)
+	if err != nil {
+		return nil, "", err
+	}
+
+	id := newJobID()
+	payload, err := json.Marshal(envelope{ID: id, N: job.Name(), Codec: codec.Name(), Payload: data, Unique: uniqueKey})
+	return payload, id, err
+}
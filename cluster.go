@@ -0,0 +1,276 @@
+package goku
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// numClusterSlots is the fixed slot count a Redis Cluster partitions its
+// keyspace into.
+const numClusterSlots = 16384
+
+// clusterPool is a minimal Redis Cluster client: it learns the slot map
+// from CLUSTER SLOTS against a seed node, then routes each command to the
+// *redis.Pool owning that command's key, using the same {queue} hash tags
+// hashTagKey already puts on every multi-key operation so related keys
+// always land on one node.
+type clusterPool struct {
+	seedAddrs []string
+	timeout   time.Duration
+
+	mu        sync.RWMutex
+	slots     [numClusterSlots]*redis.Pool
+	nodePools map[string]*redis.Pool
+}
+
+// newClusterPool returns a RedisPool backed by a Redis Cluster, seeded
+// from addrs and refreshed once on startup to learn the slot map.
+func newClusterPool(addrs []string, timeout time.Duration) (RedisPool, error) {
+	cluster := &clusterPool{
+		seedAddrs: addrs,
+		timeout:   timeout,
+		nodePools: make(map[string]*redis.Pool),
+	}
+
+	if err := cluster.refresh(); err != nil {
+		return nil, err
+	}
+
+	return cluster, nil
+}
+
+// Get implements RedisPool.
+func (cp *clusterPool) Get() redis.Conn {
+	return &clusterConn{cluster: cp}
+}
+
+// refresh re-dials a seed node, runs CLUSTER SLOTS, and rebuilds the slot
+// map from its reply.
+func (cp *clusterPool) refresh() error {
+	var lastErr error
+
+	for _, addr := range cp.seedAddrs {
+		conn, err := redis.DialTimeout("tcp", addr, cp.timeout, cp.timeout, cp.timeout)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		slots, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+		conn.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		cp.applySlots(slots)
+		return nil
+	}
+
+	return lastErr
+}
+
+func (cp *clusterPool) applySlots(slots []interface{}) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	for _, s := range slots {
+		entry, err := redis.Values(s, nil)
+		if err != nil || len(entry) < 3 {
+			continue
+		}
+
+		start, err := redis.Int(entry[0], nil)
+		if err != nil {
+			continue
+		}
+		end, err := redis.Int(entry[1], nil)
+		if err != nil {
+			continue
+		}
+
+		master, err := redis.Values(entry[2], nil)
+		if err != nil || len(master) < 2 {
+			continue
+		}
+		ip, err := redis.String(master[0], nil)
+		if err != nil {
+			continue
+		}
+		port, err := redis.Int(master[1], nil)
+		if err != nil {
+			continue
+		}
+
+		addr := fmt.Sprintf("%s:%d", ip, port)
+		pool, ok := cp.nodePools[addr]
+		if !ok {
+			pool = newRedisPool(addr, cp.timeout)
+			cp.nodePools[addr] = pool
+		}
+
+		for slot := start; slot <= end && slot < numClusterSlots; slot++ {
+			cp.slots[slot] = pool
+		}
+	}
+}
+
+// poolForArgs returns the node pool owning the key a command's first
+// argument names (for EVAL and EVALSHA, the first key after the script and
+// key count), falling back to any known node for keyless commands or keys
+// the slot map hasn't learned about yet.
+func (cp *clusterPool) poolForArgs(cmd string, args []interface{}) (*redis.Pool, error) {
+	keyArgs := args
+	if cmd == "EVAL" || cmd == "EVALSHA" {
+		keyArgs = nil
+		if len(args) > 2 {
+			keyArgs = args[2:]
+		}
+	}
+
+	if len(keyArgs) > 0 {
+		if key, ok := toRedisKey(keyArgs[0]); ok {
+			cp.mu.RLock()
+			pool := cp.slots[keySlot(key)]
+			cp.mu.RUnlock()
+			if pool != nil {
+				return pool, nil
+			}
+		}
+	}
+
+	return cp.anyPool()
+}
+
+func (cp *clusterPool) anyPool() (*redis.Pool, error) {
+	cp.mu.RLock()
+	defer cp.mu.RUnlock()
+
+	for _, pool := range cp.nodePools {
+		return pool, nil
+	}
+
+	return nil, errors.New("goku: no cluster nodes available")
+}
+
+func toRedisKey(v interface{}) (string, bool) {
+	switch key := v.(type) {
+	case string:
+		return key, true
+	case []byte:
+		return string(key), true
+	default:
+		return "", false
+	}
+}
+
+// keySlot returns the Cluster slot key hashes to, honoring {tag} hash
+// tags the same way hashTagKey produces them.
+func keySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key)) % numClusterSlots
+}
+
+// crc16 computes the CRC16/XMODEM checksum Redis Cluster uses for
+// slot assignment (polynomial 0x1021, zero initial value).
+func crc16(s string) uint16 {
+	var crc uint16
+	for _, b := range []byte(s) {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// clusterConn is the redis.Conn a clusterPool hands out. Single-shot
+// commands (Do) are routed independently so a loop touching several
+// queues lands each command on the right node; pipelined commands (Send,
+// Flush, Receive) share one underlying connection, which is only safe
+// because every pipeline in this package stays within one queue's hash
+// tag.
+type clusterConn struct {
+	cluster  *clusterPool
+	pipeConn redis.Conn
+}
+
+// Do implements redis.Conn.
+func (c *clusterConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	return c.doWithRetry(cmd, args, true)
+}
+
+func (c *clusterConn) doWithRetry(cmd string, args []interface{}, allowRetry bool) (interface{}, error) {
+	pool, err := c.cluster.poolForArgs(cmd, args)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	reply, err := conn.Do(cmd, args...)
+	if allowRetry && err != nil && strings.HasPrefix(err.Error(), "MOVED ") {
+		if refreshErr := c.cluster.refresh(); refreshErr == nil {
+			return c.doWithRetry(cmd, args, false)
+		}
+	}
+	return reply, err
+}
+
+// Send implements redis.Conn.
+func (c *clusterConn) Send(cmd string, args ...interface{}) error {
+	if c.pipeConn == nil {
+		pool, err := c.cluster.poolForArgs(cmd, args)
+		if err != nil {
+			return err
+		}
+		c.pipeConn = pool.Get()
+	}
+	return c.pipeConn.Send(cmd, args...)
+}
+
+// Flush implements redis.Conn.
+func (c *clusterConn) Flush() error {
+	if c.pipeConn == nil {
+		return nil
+	}
+	return c.pipeConn.Flush()
+}
+
+// Receive implements redis.Conn.
+func (c *clusterConn) Receive() (interface{}, error) {
+	if c.pipeConn == nil {
+		return nil, errors.New("goku: Receive called before Send")
+	}
+	return c.pipeConn.Receive()
+}
+
+// Err implements redis.Conn.
+func (c *clusterConn) Err() error {
+	if c.pipeConn == nil {
+		return nil
+	}
+	return c.pipeConn.Err()
+}
+
+// Close implements redis.Conn.
+func (c *clusterConn) Close() error {
+	if c.pipeConn == nil {
+		return nil
+	}
+	return c.pipeConn.Close()
+}
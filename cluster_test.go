@@ -0,0 +1,24 @@
+package goku
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeySlotHonorsHashTag(t *testing.T) {
+	assert := assert.New(t)
+
+	a := keySlot(hashTagKey("goku_test", scheduledSuffix))
+	b := keySlot(hashTagKey("goku_test", deadLetterSuffix))
+	assert.Equal(a, b, "keys sharing a {queue} hash tag must land on the same slot")
+}
+
+func TestKeySlotInRange(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, key := range []string{"foo", "{tag}bar", "", "{unterminated"} {
+		slot := keySlot(key)
+		assert.True(slot >= 0 && slot < numClusterSlots, "keySlot(%q) = %d out of range", key, slot)
+	}
+}
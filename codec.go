@@ -0,0 +1,103 @@
+package goku
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/vmihailenco/msgpack"
+)
+
+// Codec marshals and unmarshals a Job's own fields to and from bytes.
+// BrokerConfig.Codec selects which one new jobs are enqueued with; every
+// registered codec stays available to unmarshal, identified by Name in
+// the envelope, so a queue can safely mix jobs enqueued under different
+// codecs (e.g. while migrating from JSON to protobuf).
+type Codec interface {
+	// Name identifies this codec in the envelope.
+	Name() string
+	Marshal(job Job) ([]byte, error)
+	Unmarshal(data []byte, job Job) error
+}
+
+var codecs = map[string]Codec{}
+
+func init() {
+	for _, codec := range []Codec{JSONCodec{}, MsgpackCodec{}, ProtobufCodec{}} {
+		RegisterCodec(codec)
+	}
+}
+
+// RegisterCodec makes codec available for unmarshaling jobs by name.
+// goku's built-in codecs are registered automatically; call this to add
+// a custom one before it's referenced by BrokerConfig.Codec.
+func RegisterCodec(codec Codec) {
+	codecs[codec.Name()] = codec
+}
+
+func codecByName(name string) (Codec, error) {
+	codec, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("goku: unknown codec %q", name)
+	}
+	return codec, nil
+}
+
+// JSONCodec marshals a job with encoding/json. It's goku's default codec.
+type JSONCodec struct{}
+
+// Name implements Codec.
+func (JSONCodec) Name() string { return "json" }
+
+// Marshal implements Codec.
+func (JSONCodec) Marshal(job Job) ([]byte, error) {
+	return json.Marshal(job)
+}
+
+// Unmarshal implements Codec.
+func (JSONCodec) Unmarshal(data []byte, job Job) error {
+	return json.Unmarshal(data, job)
+}
+
+// MsgpackCodec marshals a job with MessagePack, trading JSON's
+// human-readability for a smaller payload and less reflection overhead.
+type MsgpackCodec struct{}
+
+// Name implements Codec.
+func (MsgpackCodec) Name() string { return "msgpack" }
+
+// Marshal implements Codec.
+func (MsgpackCodec) Marshal(job Job) ([]byte, error) {
+	return msgpack.Marshal(job)
+}
+
+// Unmarshal implements Codec.
+func (MsgpackCodec) Unmarshal(data []byte, job Job) error {
+	return msgpack.Unmarshal(data, job)
+}
+
+// ProtobufCodec marshals a job with protocol buffers. Jobs used with this
+// codec must implement proto.Message, so their schema can evolve without
+// breaking payloads already sitting on a queue.
+type ProtobufCodec struct{}
+
+// Name implements Codec.
+func (ProtobufCodec) Name() string { return "protobuf" }
+
+// Marshal implements Codec.
+func (ProtobufCodec) Marshal(job Job) ([]byte, error) {
+	msg, ok := job.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("goku: %T does not implement proto.Message", job)
+	}
+	return proto.Marshal(msg)
+}
+
+// Unmarshal implements Codec.
+func (ProtobufCodec) Unmarshal(data []byte, job Job) error {
+	msg, ok := job.(proto.Message)
+	if !ok {
+		return fmt.Errorf("goku: %T does not implement proto.Message", job)
+	}
+	return proto.Unmarshal(data, msg)
+}
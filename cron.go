@@ -0,0 +1,115 @@
+package goku
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed 5-field cron expression (minute hour
+// day-of-month month day-of-week), each field a bitmask of the values it
+// matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+}
+
+// parseCron parses a standard 5-field cron expression such as "0 2 * * *".
+// Each field supports "*", single values, comma lists, "a-b" ranges, and
+// "*/n" or "a-b/n" steps.
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("goku: cron spec %q must have 5 fields, got %d", spec, len(fields))
+	}
+
+	minute, err := parseCronField(fields[0], 0, 59)
+	if err != nil {
+		return nil, err
+	}
+	hour, err := parseCronField(fields[1], 0, 23)
+	if err != nil {
+		return nil, err
+	}
+	dom, err := parseCronField(fields[2], 1, 31)
+	if err != nil {
+		return nil, err
+	}
+	month, err := parseCronField(fields[3], 1, 12)
+	if err != nil {
+		return nil, err
+	}
+	dow, err := parseCronField(fields[4], 0, 6)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cronSchedule{minute: minute, hour: hour, dom: dom, month: month, dow: dow}, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var mask uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+
+		if i := strings.Index(part, "/"); i >= 0 {
+			rng = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("goku: invalid cron step %q", part)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rng == "*":
+			// lo/hi already cover the full range.
+		case strings.Contains(rng, "-"):
+			bounds := strings.SplitN(rng, "-", 2)
+			a, err1 := strconv.Atoi(bounds[0])
+			b, err2 := strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil || a < min || b > max || a > b {
+				return 0, fmt.Errorf("goku: invalid cron range %q", rng)
+			}
+			lo, hi = a, b
+		default:
+			v, err := strconv.Atoi(rng)
+			if err != nil || v < min || v > max {
+				return 0, fmt.Errorf("goku: invalid cron value %q", rng)
+			}
+			lo, hi = v, v
+		}
+
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+
+	return mask, nil
+}
+
+func (s *cronSchedule) matches(t time.Time) bool {
+	return s.minute&(1<<uint(t.Minute())) != 0 &&
+		s.hour&(1<<uint(t.Hour())) != 0 &&
+		s.dom&(1<<uint(t.Day())) != 0 &&
+		s.month&(1<<uint(t.Month())) != 0 &&
+		s.dow&(1<<uint(t.Weekday())) != 0
+}
+
+// next returns the first minute-aligned time strictly after t that
+// satisfies the schedule.
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// A valid cron expression always matches within four years; this bound
+	// just guards against expressions that can never match (e.g. Feb 30).
+	for limit := t.Add(4 * 365 * 24 * time.Hour); t.Before(limit); t = t.Add(time.Minute) {
+		if s.matches(t) {
+			return t
+		}
+	}
+
+	return t
+}
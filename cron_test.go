@@ -0,0 +1,41 @@
+package goku
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCronBadSpec(t *testing.T) {
+	_, err := parseCron("* * *")
+	assert.Error(t, err)
+
+	_, err = parseCron("60 * * * *")
+	assert.Error(t, err)
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	require := require.New(t)
+
+	schedule, err := parseCron("30 2 * * *")
+	require.NoError(err)
+
+	from := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	next := schedule.next(from)
+
+	assert.Equal(t, time.Date(2020, time.January, 1, 2, 30, 0, 0, time.UTC), next)
+}
+
+func TestCronScheduleNextEveryMinute(t *testing.T) {
+	require := require.New(t)
+
+	schedule, err := parseCron("* * * * *")
+	require.NoError(err)
+
+	from := time.Date(2020, time.January, 1, 0, 0, 30, 0, time.UTC)
+	next := schedule.next(from)
+
+	assert.Equal(t, time.Date(2020, time.January, 1, 0, 1, 0, 0, time.UTC), next)
+}
@@ -0,0 +1,17 @@
+package goku
+
+import "errors"
+
+var (
+	// ErrPointer is returned by Run/RunAt when passed a pointer to a Job
+	// rather than a value. Jobs are marshaled and later re-hydrated by
+	// value, so a pointer can't round-trip through the queue.
+	ErrPointer = errors.New("goku: job must be passed by value, not a pointer")
+
+	errMissingHostport = errors.New("goku: Hostport is required")
+	errMissingQueue    = errors.New("goku: DefaultQueue is required")
+	errNoQueues        = errors.New("goku: at least one queue is required")
+	errNoWorkers       = errors.New("goku: NumWorkers must be greater than 0")
+
+	errJobTimedOut = errors.New("goku: job timed out")
+)
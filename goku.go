@@ -0,0 +1,33 @@
+// Package goku is a small Redis-backed job queue: a Broker enqueues jobs
+// and a WorkerPool executes them.
+package goku
+
+import "sync"
+
+var (
+	defaultBroker   *Broker
+	defaultBrokerMu sync.RWMutex
+)
+
+// Configure sets up the package-level default Broker used by Run.
+func Configure(config BrokerConfig) error {
+	broker, err := NewBroker(config)
+	if err != nil {
+		return err
+	}
+
+	defaultBrokerMu.Lock()
+	defaultBroker = broker
+	defaultBrokerMu.Unlock()
+
+	return nil
+}
+
+// Run enqueues job onto the default broker configured via Configure.
+func Run(job Job) error {
+	defaultBrokerMu.RLock()
+	broker := defaultBroker
+	defaultBrokerMu.RUnlock()
+
+	return broker.Run(job)
+}
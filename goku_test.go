@@ -2,6 +2,10 @@ package goku
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -86,13 +90,113 @@ func TestBroker(t *testing.T) {
 	jsn, err := redis.Bytes(conn.Do("LPOP", queueName))
 	assert.NoError(err)
 
-	var m map[string]interface{}
-	json.Unmarshal(jsn, &m)
-	args := m["A"].(map[string]interface{})
+	var env envelope
+	require.NoError(json.Unmarshal(jsn, &env))
 
-	assert.Equal(m["N"], job.Name())
-	assert.Equal(args["Foo"], float64(4))
-	assert.Equal(args["Bar"], "sup")
+	assert.Equal(job.Name(), env.N)
+	assert.Equal("json", env.Codec)
+
+	var decoded TestJob
+	require.NoError(json.Unmarshal(env.Payload, &decoded))
+	assert.Equal(job, decoded)
+}
+
+func TestRunWithMsgpackCodec(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	hostport := "127.0.0.1:6379"
+	queueName := "goku_test"
+
+	broker, err := NewBroker(BrokerConfig{
+		Hostport:     hostport,
+		Timeout:      time.Second,
+		DefaultQueue: queueName,
+		Codec:        MsgpackCodec{},
+	})
+	require.NoError(err)
+
+	job := TestJob{Foo: 4, Bar: "sup"}
+	err = broker.Run(job)
+	assert.NoError(err)
+
+	conn, err := redis.Dial("tcp", hostport)
+	require.NoError(err)
+	defer conn.Close()
+
+	jsn, err := redis.Bytes(conn.Do("LPOP", queueName))
+	assert.NoError(err)
+
+	var env envelope
+	require.NoError(json.Unmarshal(jsn, &env))
+	assert.Equal("msgpack", env.Codec)
+
+	codec, err := codecByName(env.Codec)
+	require.NoError(err)
+
+	decoded, err := hydrateJob(TestJob{}, codec, env.Payload)
+	require.NoError(err)
+	assert.Equal(job, decoded)
+}
+
+// TestProtoJob stands in for a protoc-generated message: ProtobufCodec
+// only needs Reset/String/ProtoMessage to treat a Job as a proto.Message.
+// Reset and String take value receivers, unlike generated code, so that
+// TestProtoJob itself (not just *TestProtoJob) satisfies proto.Message —
+// Run requires jobs be passed by value.
+type TestProtoJob struct {
+	Foo int32  `protobuf:"varint,1,opt,name=foo,proto3" json:"foo,omitempty"`
+	Bar string `protobuf:"bytes,2,opt,name=bar,proto3" json:"bar,omitempty"`
+}
+
+func (tj TestProtoJob) Reset()         {}
+func (tj TestProtoJob) String() string { return fmt.Sprintf("%+v", tj) }
+func (TestProtoJob) ProtoMessage()     {}
+
+func (tj TestProtoJob) Name() string {
+	return "test_proto_job"
+}
+
+func (tj TestProtoJob) Execute(_ TimeoutChan) error {
+	return nil
+}
+
+func TestRunWithProtobufCodec(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	hostport := "127.0.0.1:6379"
+	queueName := "goku_test"
+
+	broker, err := NewBroker(BrokerConfig{
+		Hostport:     hostport,
+		Timeout:      time.Second,
+		DefaultQueue: queueName,
+		Codec:        ProtobufCodec{},
+	})
+	require.NoError(err)
+
+	job := TestProtoJob{Foo: 4, Bar: "sup"}
+	err = broker.Run(job)
+	assert.NoError(err)
+
+	conn, err := redis.Dial("tcp", hostport)
+	require.NoError(err)
+	defer conn.Close()
+
+	jsn, err := redis.Bytes(conn.Do("LPOP", queueName))
+	assert.NoError(err)
+
+	var env envelope
+	require.NoError(json.Unmarshal(jsn, &env))
+	assert.Equal("protobuf", env.Codec)
+
+	codec, err := codecByName(env.Codec)
+	require.NoError(err)
+
+	decoded, err := hydrateJob(TestProtoJob{}, codec, env.Payload)
+	require.NoError(err)
+	assert.Equal(job, decoded)
 }
 
 func TestRun(t *testing.T) {
@@ -290,3 +394,302 @@ func TestRunAt(t *testing.T) {
 	wp.Stop()
 	assert.True(tjWasCalled)
 }
+
+func TestRunEmitsStatusHookEvents(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	queue := "goku_test_status"
+	hostport := "127.0.0.1:6379"
+
+	var mu sync.Mutex
+	var events []StatusEvent
+
+	hook := StatusHook{
+		Func: func(event StatusEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event)
+		},
+	}
+
+	config := WorkerConfig{
+		NumWorkers: 1,
+		Queues:     []string{queue},
+		Hostport:   hostport,
+		Timeout:    time.Second,
+	}
+
+	opts := WorkerPoolOptions{
+		Jobs:       []Job{TestJob{}},
+		StatusHook: hook,
+	}
+
+	wp, err := NewWorkerPool(config, opts)
+	require.NoError(err)
+	wp.Start()
+
+	broker, err := NewBroker(BrokerConfig{
+		Hostport:     hostport,
+		Timeout:      time.Second,
+		DefaultQueue: queue,
+		StatusHook:   hook,
+	})
+	require.NoError(err)
+
+	err = broker.Run(TestJob{Foo: 1, Bar: "status"})
+	require.NoError(err)
+
+	time.Sleep(time.Second)
+	wp.Stop()
+
+	mu.Lock()
+	statuses := make([]JobStatus, 0, len(events))
+	var jobID string
+	for _, e := range events {
+		statuses = append(statuses, e.Status)
+		jobID = e.JobID
+	}
+	mu.Unlock()
+
+	assert.Contains(statuses, StatusQueued)
+	assert.Contains(statuses, StatusRunning)
+	assert.Contains(statuses, StatusSucceeded)
+	require.NotEmpty(jobID)
+
+	// persistStatus writes asynchronously; give it a moment to land before
+	// checking Redis directly.
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := redis.Dial("tcp", hostport)
+	require.NoError(err)
+	defer conn.Close()
+
+	raw, err := redis.Bytes(conn.Do("GET", statusKey(jobID)))
+	require.NoError(err)
+
+	var persisted StatusEvent
+	require.NoError(json.Unmarshal(raw, &persisted))
+	assert.Equal(StatusSucceeded, persisted.Status)
+}
+
+type TestCountingJob struct{}
+
+func (tj TestCountingJob) Name() string {
+	return "test_counting_job"
+}
+
+var testCountingJobCount int32
+
+func (tj TestCountingJob) Execute(_ TimeoutChan) error {
+	atomic.AddInt32(&testCountingJobCount, 1)
+	return nil
+}
+
+func TestRunPeriodicFiresRepeatedly(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	queue := "goku_test_periodic"
+	hostport := "127.0.0.1:6379"
+
+	config := WorkerConfig{
+		NumWorkers: 1,
+		Queues:     []string{queue},
+		Hostport:   hostport,
+		Timeout:    time.Second,
+	}
+
+	opts := WorkerPoolOptions{
+		Jobs: []Job{TestCountingJob{}},
+	}
+
+	wp, err := NewWorkerPool(config, opts)
+	require.NoError(err)
+	wp.Start()
+	defer wp.Stop()
+
+	broker, err := NewBroker(BrokerConfig{
+		Hostport:     hostport,
+		Timeout:      time.Second,
+		DefaultQueue: queue,
+	})
+	require.NoError(err)
+
+	atomic.StoreInt32(&testCountingJobCount, 0)
+	require.NoError(broker.RunPeriodic(TestCountingJob{}, time.Second))
+
+	// A leader lock left to expire on its own TTL (periodicLockTTL, 5s)
+	// would serialize this single Broker against itself, firing roughly
+	// once per TTL instead of once per requested interval. Releasing it
+	// right after each tick should comfortably clear 3 runs in 4.5s.
+	time.Sleep(4500 * time.Millisecond)
+
+	assert.GreaterOrEqual(int(atomic.LoadInt32(&testCountingJobCount)), 3)
+}
+
+type TestJobAlwaysFails struct {
+	Foo int
+}
+
+func (tj TestJobAlwaysFails) Name() string {
+	return "test_job_always_fails"
+}
+
+var testJobAlwaysFailsCount int
+
+func (tj TestJobAlwaysFails) Execute(_ TimeoutChan) error {
+	testJobAlwaysFailsCount++
+	return errors.New("boom")
+}
+
+func TestRunWithRetryAndDeadLetter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	queue := "goku_test_retry"
+	hostport := "127.0.0.1:6379"
+
+	config := WorkerConfig{
+		NumWorkers: 1,
+		Queues:     []string{queue},
+		Hostport:   hostport,
+		Timeout:    time.Second,
+		Retry: RetryPolicy{
+			MaxAttempts: 2,
+			BaseDelay:   time.Second,
+		},
+	}
+
+	opts := WorkerPoolOptions{
+		Jobs: []Job{
+			TestJobAlwaysFails{},
+		},
+	}
+
+	wp, err := NewWorkerPool(config, opts)
+	require.NoError(err)
+	wp.Start()
+
+	broker, err := NewBroker(BrokerConfig{
+		Hostport:     hostport,
+		Timeout:      time.Second,
+		DefaultQueue: queue,
+	})
+	require.NoError(err)
+
+	testJobAlwaysFailsCount = 0
+	err = broker.Run(TestJobAlwaysFails{Foo: 1})
+	assert.NoError(err)
+
+	// The first attempt runs immediately; the retry is scheduled onto the
+	// delayed-jobs set and only promoted once a scheduler (the pool's own
+	// retry scheduler, or the broker's) ticks, so give both enough time to
+	// land.
+	time.Sleep(3 * time.Second)
+	wp.Stop()
+
+	assert.Equal(2, testJobAlwaysFailsCount)
+
+	conn, err := redis.Dial("tcp", hostport)
+	require.NoError(err)
+	defer conn.Close()
+
+	raw, err := redis.Bytes(conn.Do("LPOP", hashTagKey(queue, deadLetterSuffix)))
+	require.NoError(err)
+
+	var entry deadLetterEntry
+	require.NoError(json.Unmarshal(raw, &entry))
+	assert.Equal("boom", entry.LastError)
+}
+
+type TestUniqueJob struct {
+	Foo int
+}
+
+func (tj TestUniqueJob) Name() string {
+	return "test_unique_job"
+}
+
+func (tj TestUniqueJob) Execute(_ TimeoutChan) error {
+	return nil
+}
+
+func (tj TestUniqueJob) UniqueKey() string {
+	return "test_unique_job"
+}
+
+func (tj TestUniqueJob) UniqueTTL() time.Duration {
+	return time.Minute
+}
+
+func TestRunUnique(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	hostport := "127.0.0.1:6379"
+	queueName := "goku_test"
+
+	conn, err := redis.Dial("tcp", hostport)
+	require.NoError(err)
+	defer conn.Close()
+	conn.Do("DEL", uniqueKeyPrefix+"test_unique_job")
+
+	broker, err := NewBroker(BrokerConfig{
+		Hostport:     hostport,
+		Timeout:      time.Second,
+		DefaultQueue: queueName,
+	})
+	require.NoError(err)
+
+	err = broker.RunUnique(TestUniqueJob{Foo: 1})
+	assert.NoError(err)
+
+	err = broker.RunUnique(TestUniqueJob{Foo: 2})
+	assert.Equal(ErrDuplicate, err)
+}
+
+func TestReliableFetchReapsCrashedWorker(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	queue := "goku_test_reliable"
+	hostport := "127.0.0.1:6379"
+
+	conn, err := redis.Dial("tcp", hostport)
+	require.NoError(err)
+	defer conn.Close()
+	conn.Do("DEL", queue)
+
+	config := WorkerConfig{
+		NumWorkers:    1,
+		Queues:        []string{queue},
+		Hostport:      hostport,
+		Timeout:       time.Second,
+		ReliableFetch: true,
+	}
+
+	opts := WorkerPoolOptions{
+		Jobs: []Job{TestJob{}},
+	}
+
+	wp, err := NewWorkerPool(config, opts)
+	require.NoError(err)
+
+	// Simulate a worker that popped a job into its in-flight list and
+	// then crashed before finishing it, without ever sending a heartbeat.
+	workerID := "dead-worker"
+	payload, _, err := marshalJob(TestJob{Foo: 4, Bar: "sup"}, "", JSONCodec{})
+	require.NoError(err)
+	_, err = conn.Do("RPUSH", inflightKey(workerID, queue), payload)
+	require.NoError(err)
+	_, err = conn.Do("SADD", inflightRegistryKey, registryMember(workerID, queue))
+	require.NoError(err)
+
+	tjWasCalled = false
+	wp.Start()
+	time.Sleep(heartbeatTTL + 3*time.Second)
+	wp.Stop()
+
+	assert.True(tjWasCalled)
+}
@@ -0,0 +1,29 @@
+package goku
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// randomHex returns n random bytes hex-encoded, falling back to a
+// timestamp-derived value on the rare chance the system's CSPRNG is
+// unavailable.
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return hex.EncodeToString([]byte(time.Now().String()))
+	}
+	return hex.EncodeToString(buf)
+}
+
+// newInstanceID identifies a Broker process when competing for periodic-job
+// leadership against other Broker processes.
+func newInstanceID() string {
+	return randomHex(16)
+}
+
+// newJobID identifies a single enqueued job for status tracking.
+func newJobID() string {
+	return randomHex(12)
+}
@@ -0,0 +1,18 @@
+package goku
+
+// TimeoutChan is closed by a worker once a job's configured execution
+// timeout has elapsed. Jobs that do meaningful work should select on it
+// alongside their own work so they can abort promptly.
+type TimeoutChan <-chan struct{}
+
+// Job is implemented by any type that can be enqueued and executed by goku.
+type Job interface {
+	// Name uniquely identifies the job type. It is stored alongside the
+	// job's arguments in the queue payload so a worker can look up the
+	// right Job implementation to hydrate and run.
+	Name() string
+
+	// Execute runs the job. Implementations should select on timeoutChan
+	// to abandon long-running work once the worker's timeout fires.
+	Execute(timeoutChan TimeoutChan) error
+}
@@ -0,0 +1,144 @@
+package goku
+
+import (
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// periodicSetKey is the sorted set holding the next scheduled run time
+// (score, as a unix timestamp) for every registered periodic job (member).
+const periodicSetKey = "goku:periodic"
+
+// periodicLockPrefix namespaces the leader-election locks periodic
+// scheduling uses so only one Broker process promotes a given periodic
+// job at a time.
+const periodicLockPrefix = "goku:periodic:lock:"
+
+// periodicLockTTL bounds how long a broker holds leadership for a
+// periodic job before another instance is allowed to take over, e.g. if
+// it crashes mid-tick.
+const periodicLockTTL = 5 * time.Second
+
+// dueScript atomically removes member from key if its score is <= now,
+// returning 1 if it did so and 0 otherwise.
+var dueScript = redis.NewScript(1, `
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+for _, m in ipairs(due) do
+	if m == ARGV[2] then
+		redis.call('ZREM', KEYS[1], m)
+		return 1
+	end
+end
+return 0
+`)
+
+// releaseLockScript deletes key only if it still holds value, so a
+// leader-election lock is never released out from under whoever won it
+// next (e.g. after this instance's own lock expired and was re-acquired
+// by another process).
+var releaseLockScript = redis.NewScript(1, `
+if redis.call('get', KEYS[1]) == ARGV[1] then
+	return redis.call('del', KEYS[1])
+end
+return 0
+`)
+
+// periodicEntry is a job registered via RunEvery or RunPeriodic.
+type periodicEntry struct {
+	job  Job
+	next func(time.Time) time.Time
+}
+
+// RunEvery registers job to be enqueued onto the broker's DefaultQueue on
+// the schedule described by spec, a standard 5-field cron expression
+// (minute hour day-of-month month day-of-week). When multiple Broker
+// processes share the same Redis instance, a leader-election lock
+// ensures only one of them enqueues a given occurrence.
+func (b *Broker) RunEvery(job Job, spec string) error {
+	schedule, err := parseCron(spec)
+	if err != nil {
+		return err
+	}
+
+	return b.registerPeriodic(job, schedule.next)
+}
+
+// RunPeriodic registers job to be enqueued onto the broker's DefaultQueue
+// every interval. See RunEvery for the leader-election behavior when
+// multiple Broker processes share the same Redis instance.
+func (b *Broker) RunPeriodic(job Job, interval time.Duration) error {
+	return b.registerPeriodic(job, func(t time.Time) time.Time {
+		return t.Add(interval)
+	})
+}
+
+func (b *Broker) registerPeriodic(job Job, next func(time.Time) time.Time) error {
+	conn := b.pool.Get()
+	defer conn.Close()
+
+	firstRun := next(time.Now())
+	if _, err := conn.Do("ZADD", periodicSetKey, "NX", firstRun.Unix(), job.Name()); err != nil {
+		return err
+	}
+
+	b.periodicMu.Lock()
+	b.periodic[job.Name()] = &periodicEntry{job: job, next: next}
+	b.periodicMu.Unlock()
+
+	return nil
+}
+
+// runPeriodicScheduler periodically checks every registered periodic job
+// and, while holding its leader lock, enqueues it once its schedule says
+// it's due.
+func (b *Broker) runPeriodicScheduler() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		b.promoteDuePeriodicJobs(now)
+	}
+}
+
+func (b *Broker) promoteDuePeriodicJobs(now time.Time) {
+	b.periodicMu.RLock()
+	entries := make(map[string]*periodicEntry, len(b.periodic))
+	for name, entry := range b.periodic {
+		entries[name] = entry
+	}
+	b.periodicMu.RUnlock()
+
+	conn := b.pool.Get()
+	defer conn.Close()
+
+	for name, entry := range entries {
+		b.tickPeriodicEntry(conn, name, entry, now)
+	}
+}
+
+// tickPeriodicEntry takes name's leader lock for this tick, checks
+// whether it's due, and runs it if so, releasing the lock before
+// returning so the next tick doesn't serialize against this same
+// instance for the rest of periodicLockTTL.
+func (b *Broker) tickPeriodicEntry(conn redis.Conn, name string, entry *periodicEntry, now time.Time) {
+	lockKey := periodicLockPrefix + name
+	acquired, err := redis.String(conn.Do("SET", lockKey, b.instanceID, "NX", "PX", periodicLockTTL.Milliseconds()))
+	if err != nil || acquired != "OK" {
+		// Either we lost the race for leadership or hit a Redis error;
+		// another instance (or our next tick) will pick this up.
+		return
+	}
+	defer releaseLockScript.Do(conn, lockKey, b.instanceID)
+
+	due, err := redis.Int(dueScript.Do(conn, periodicSetKey, now.Unix(), name))
+	if err != nil || due == 0 {
+		return
+	}
+
+	if err := b.Run(entry.job); err != nil {
+		return
+	}
+
+	conn.Do("ZADD", periodicSetKey, entry.next(now).Unix(), name)
+}
@@ -0,0 +1,78 @@
+package goku
+
+import (
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// RedisPool is the minimal surface goku needs from a Redis connection
+// pool. It's satisfied by *redis.Pool directly, which lets BrokerConfig
+// and WorkerConfig accept a caller-supplied Pool for topologies or
+// connection options goku doesn't build itself.
+type RedisPool interface {
+	Get() redis.Conn
+}
+
+// redisConfig gathers the Redis connection fields BrokerConfig and
+// WorkerConfig share (see their redisConfig methods) to select a
+// single-node, Sentinel, or Cluster Redis deployment. Exactly one of
+// Hostport, SentinelAddrs, or ClusterAddrs should be set, unless Pool is
+// supplied to bypass goku's own pool construction entirely.
+type redisConfig struct {
+	// Hostport is the address of a single Redis instance.
+	Hostport string
+
+	// SentinelAddrs lists Sentinel addresses used to discover
+	// MasterName's current master.
+	SentinelAddrs []string
+
+	// MasterName is the Sentinel-monitored master name to connect to.
+	MasterName string
+
+	// ClusterAddrs lists seed addresses for a Redis Cluster deployment.
+	ClusterAddrs []string
+
+	// Pool, if set, overrides goku's own pool construction above.
+	Pool RedisPool
+}
+
+// newPool builds the RedisPool described by cfg, bounding connection
+// operations by timeout.
+func newPool(cfg redisConfig, timeout time.Duration) (RedisPool, error) {
+	switch {
+	case cfg.Pool != nil:
+		return cfg.Pool, nil
+	case len(cfg.ClusterAddrs) > 0:
+		return newClusterPool(cfg.ClusterAddrs, timeout)
+	case len(cfg.SentinelAddrs) > 0:
+		return newSentinelPool(cfg.SentinelAddrs, cfg.MasterName, timeout), nil
+	case cfg.Hostport != "":
+		return newRedisPool(cfg.Hostport, timeout), nil
+	default:
+		return nil, errMissingHostport
+	}
+}
+
+// newRedisPool returns a redis.Pool dialing hostport, bounding every
+// connection operation by timeout.
+func newRedisPool(hostport string, timeout time.Duration) *redis.Pool {
+	return &redis.Pool{
+		MaxIdle:     10,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			return redis.DialTimeout("tcp", hostport, timeout, timeout, timeout)
+		},
+		TestOnBorrow: func(conn redis.Conn, t time.Time) error {
+			_, err := conn.Do("PING")
+			return err
+		},
+	}
+}
+
+// hashTagKey returns suffix's key name for queue, hash-tagged with {queue}
+// so it lands on the same Cluster slot as queue's own list key, letting
+// the two be touched atomically by the same Lua script.
+func hashTagKey(queue, suffix string) string {
+	return "{" + queue + "}" + suffix
+}
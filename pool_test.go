@@ -0,0 +1,100 @@
+package goku
+
+import (
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConn is a minimal redis.Conn that records the commands sent to it,
+// standing in for a real connection in tests that only need to verify
+// goku routed through a caller-supplied Pool rather than dialing one itself.
+type fakeConn struct {
+	cmds [][]interface{}
+}
+
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Err() error   { return nil }
+
+func (c *fakeConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	c.cmds = append(c.cmds, append([]interface{}{cmd}, args...))
+	return nil, nil
+}
+
+func (c *fakeConn) Send(cmd string, args ...interface{}) error {
+	_, err := c.Do(cmd, args...)
+	return err
+}
+
+func (c *fakeConn) Flush() error                  { return nil }
+func (c *fakeConn) Receive() (interface{}, error) { return nil, nil }
+
+// fakePool is a RedisPool that always hands out the same fakeConn.
+type fakePool struct {
+	conn *fakeConn
+}
+
+func (p *fakePool) Get() redis.Conn { return p.conn }
+
+func TestNewBrokerUsesCustomPool(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	conn := &fakeConn{}
+	broker, err := NewBroker(BrokerConfig{
+		Pool:         &fakePool{conn: conn},
+		DefaultQueue: "goku_test_custom_pool",
+	})
+	require.NoError(err)
+
+	err = broker.Run(TestJob{Foo: 1, Bar: "pool"})
+	assert.NoError(err)
+	require.Len(conn.cmds, 1)
+	assert.Equal("LPUSH", conn.cmds[0][0])
+}
+
+func TestNewWorkerPoolUsesCustomPool(t *testing.T) {
+	require := require.New(t)
+
+	conn := &fakeConn{}
+	wp, err := NewWorkerPool(WorkerConfig{
+		NumWorkers: 1,
+		Queues:     []string{"goku_test_custom_pool"},
+		Pool:       &fakePool{conn: conn},
+	}, WorkerPoolOptions{Jobs: []Job{TestJob{}}})
+	require.NoError(err)
+	require.NotNil(wp)
+}
+
+func TestNewBrokerSentinelAddrsRoutesThroughSentinel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Sentinel pool construction is lazy: NewBroker succeeds without
+	// dialing anything, and only fails once Run actually tries to resolve
+	// MasterName's address through Sentinel.
+	broker, err := NewBroker(BrokerConfig{
+		SentinelAddrs: []string{"127.0.0.1:1"},
+		MasterName:    "mymaster",
+		Timeout:       100 * time.Millisecond,
+		DefaultQueue:  "goku_test",
+	})
+	require.NoError(err)
+
+	err = broker.Run(TestJob{Foo: 1})
+	assert.Error(err)
+}
+
+func TestNewBrokerClusterAddrsUnreachable(t *testing.T) {
+	// Cluster pool construction eagerly runs CLUSTER SLOTS against a seed
+	// node, so NewBroker itself surfaces the failure to reach it.
+	_, err := NewBroker(BrokerConfig{
+		ClusterAddrs: []string{"127.0.0.1:1"},
+		Timeout:      100 * time.Millisecond,
+		DefaultQueue: "goku_test",
+	})
+	assert.Error(t, err)
+}
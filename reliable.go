@@ -0,0 +1,154 @@
+package goku
+
+import (
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// inflightRegistryKey is a set of "<workerID>:<queue>" members the reaper
+// scans to find in-flight lists that might need requeuing.
+const inflightRegistryKey = "goku:inflight:registry"
+
+// heartbeatTTL bounds how long a worker is considered alive after its
+// last heartbeat; once it expires, the reaper assumes the worker crashed.
+const heartbeatTTL = 5 * time.Second
+
+// heartbeatInterval is how often a worker refreshes its heartbeat key,
+// comfortably inside heartbeatTTL.
+const heartbeatInterval = 2 * time.Second
+
+// inflightKey hash-tags its queue the same way scheduledSuffix and
+// deadLetterSuffix do, so BRPOPLPUSH from queue into its in-flight list
+// never crosses a Cluster slot boundary.
+func inflightKey(workerID, queue string) string {
+	return hashTagKey(queue, ":inflight:"+workerID)
+}
+
+func heartbeatKey(workerID string) string {
+	return "goku:worker:" + workerID + ":heartbeat"
+}
+
+func registryMember(workerID, queue string) string {
+	return workerID + ":" + queue
+}
+
+func splitRegistryMember(member string) (workerID, queue string, ok bool) {
+	i := strings.LastIndex(member, ":")
+	if i < 0 {
+		return "", "", false
+	}
+	return member[:i], member[i+1:], true
+}
+
+// fetchReliable pulls the next job off one of wp.config.Queues with
+// BRPOPLPUSH into workerID's per-queue in-flight list, so the payload
+// survives a crash between the pop and Execute returning; the reaper can
+// later requeue it from the in-flight list.
+func (wp *WorkerPool) fetchReliable(workerID string) (queue string, payload []byte) {
+	conn := wp.pool.Get()
+	defer conn.Close()
+
+	for _, q := range wp.config.Queues {
+		dest := inflightKey(workerID, q)
+		conn.Do("SADD", inflightRegistryKey, registryMember(workerID, q))
+
+		raw, err := redis.Bytes(conn.Do("BRPOPLPUSH", q, dest, 1))
+		if err != nil {
+			continue
+		}
+
+		return q, raw
+	}
+
+	return "", nil
+}
+
+// ackInflight removes payload from workerID's in-flight list for queue
+// once it's been fully handled (succeeded, retried, or dead-lettered).
+func (wp *WorkerPool) ackInflight(workerID, queue string, payload []byte) {
+	conn := wp.pool.Get()
+	defer conn.Close()
+
+	conn.Do("LREM", inflightKey(workerID, queue), 1, payload)
+}
+
+func (wp *WorkerPool) sendHeartbeat(workerID string) {
+	conn := wp.pool.Get()
+	defer conn.Close()
+
+	conn.Do("SET", heartbeatKey(workerID), "1", "PX", heartbeatTTL.Milliseconds())
+}
+
+// runHeartbeat keeps workerID's heartbeat key alive until the pool stops.
+func (wp *WorkerPool) runHeartbeat(workerID string) {
+	wp.sendHeartbeat(workerID)
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-wp.stop:
+			return
+		case <-ticker.C:
+			wp.sendHeartbeat(workerID)
+		}
+	}
+}
+
+// runReaper periodically requeues in-flight jobs belonging to workers
+// whose heartbeat has expired, recovering payloads a crashed worker
+// popped but never finished executing.
+func (wp *WorkerPool) runReaper() {
+	ticker := time.NewTicker(heartbeatTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-wp.stop:
+			return
+		case <-ticker.C:
+			wp.reapOnce()
+		}
+	}
+}
+
+func (wp *WorkerPool) reapOnce() {
+	conn := wp.pool.Get()
+	defer conn.Close()
+
+	members, err := redis.Strings(conn.Do("SMEMBERS", inflightRegistryKey))
+	if err != nil {
+		return
+	}
+
+	for _, member := range members {
+		workerID, queue, ok := splitRegistryMember(member)
+		if !ok {
+			continue
+		}
+
+		alive, err := redis.Int(conn.Do("EXISTS", heartbeatKey(workerID)))
+		if err != nil || alive == 1 {
+			continue
+		}
+
+		requeueInflight(conn, workerID, queue)
+	}
+}
+
+// requeueInflight drains workerID's in-flight list for queue back onto
+// the tail of queue, oldest entry first.
+func requeueInflight(conn redis.Conn, workerID, queue string) {
+	key := inflightKey(workerID, queue)
+
+	for {
+		raw, err := redis.Bytes(conn.Do("RPOP", key))
+		if err != nil {
+			return
+		}
+		conn.Do("RPUSH", queue, raw)
+	}
+}
@@ -0,0 +1,206 @@
+package goku
+
+import (
+	"encoding/json"
+	"math/rand"
+	"runtime/debug"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// deadLetterSuffix is appended to a queue's name to get the list that
+// jobs are moved to once they've exhausted their retry policy.
+const deadLetterSuffix = ":dead"
+
+// RetryPolicy controls how a WorkerPool retries a job after Execute
+// returns an error or the job times out. A retry is scheduled onto its
+// queue's :scheduled sorted set — the same one RunAt uses — and the pool
+// promotes its own due retries back onto each of its Queues (see
+// WorkerPool.runRetryScheduler), independent of whether a Broker is also
+// running against that queue.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of times a job may run before it's
+	// moved to its queue's dead-letter list. Zero disables retries.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the computed backoff delay.
+	MaxDelay time.Duration
+
+	// Jitter adds up to this much random delay on top of the backoff, to
+	// avoid every failed job retrying in lockstep.
+	Jitter time.Duration
+}
+
+// RetryableJob lets a Job override the WorkerPool's default RetryPolicy.
+type RetryableJob interface {
+	Job
+
+	// Retries returns the maximum number of attempts for this job.
+	Retries() int
+
+	// Backoff returns the delay before the given attempt (1-indexed) runs.
+	Backoff(attempt int) time.Duration
+}
+
+// deadLetterEntry is the record written to a queue's dead-letter list once
+// a job exhausts its retries.
+type deadLetterEntry struct {
+	Envelope  envelope  `json:"envelope"`
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+	Stack     string    `json:"stack"`
+}
+
+func backoffDelay(policy RetryPolicy, attempt int) time.Duration {
+	delay := policy.BaseDelay * time.Duration(int64(1)<<uint(attempt-1))
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	if policy.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(policy.Jitter)))
+	}
+	return delay
+}
+
+// retryPolicy returns the max attempts and per-attempt backoff to use for
+// job, preferring its own RetryableJob implementation over the pool's
+// default RetryPolicy.
+func (wp *WorkerPool) retryPolicy(job Job) (maxAttempts int, backoff func(attempt int) time.Duration) {
+	if rj, ok := job.(RetryableJob); ok {
+		return rj.Retries(), rj.Backoff
+	}
+
+	policy := wp.config.Retry
+	return policy.MaxAttempts, func(attempt int) time.Duration {
+		return backoffDelay(policy, attempt)
+	}
+}
+
+// retryOrDeadLetter schedules env for another attempt on queue's
+// delayed-jobs sorted set, or moves it to queue's dead-letter list if its
+// retry policy has been exhausted. It reports whether a retry was
+// scheduled.
+func (wp *WorkerPool) retryOrDeadLetter(queue string, env envelope, jobErr error, job Job) bool {
+	maxAttempts, backoff := wp.retryPolicy(job)
+	nextAttempt := env.Attempt + 1
+
+	if maxAttempts <= 0 || nextAttempt >= maxAttempts {
+		wp.deadLetter(queue, env, jobErr)
+		return false
+	}
+
+	env.Attempt = nextAttempt
+	payload, err := json.Marshal(env)
+	if err != nil {
+		wp.deadLetter(queue, env, jobErr)
+		return false
+	}
+
+	conn := wp.pool.Get()
+	defer conn.Close()
+
+	delay := backoff(nextAttempt)
+	conn.Do("ZADD", hashTagKey(queue, scheduledSuffix), time.Now().Add(delay).Unix(), payload)
+	return true
+}
+
+// runRetryScheduler periodically promotes due retries from the pool's own
+// Queues back onto them, so retries resolve whether or not a Broker is
+// also running with one of these queues as its DefaultQueue.
+func (wp *WorkerPool) runRetryScheduler() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-wp.stop:
+			return
+		case <-ticker.C:
+			wp.promoteDueRetries()
+		}
+	}
+}
+
+func (wp *WorkerPool) promoteDueRetries() {
+	conn := wp.pool.Get()
+	defer conn.Close()
+
+	now := time.Now()
+	for _, queue := range wp.config.Queues {
+		due, err := promoteDueScheduled(conn, queue, now)
+		if err != nil || len(due) == 0 {
+			continue
+		}
+
+		for _, payload := range due {
+			var env envelope
+			if err := json.Unmarshal([]byte(payload), &env); err == nil {
+				emitStatus(wp.pool, wp.opts.StatusHook, env.ID, env.N, StatusQueued)
+			}
+		}
+	}
+}
+
+func (wp *WorkerPool) deadLetter(queue string, env envelope, jobErr error) {
+	lastError := ""
+	if jobErr != nil {
+		lastError = jobErr.Error()
+	}
+
+	payload, err := json.Marshal(deadLetterEntry{
+		Envelope:  env,
+		LastError: lastError,
+		FailedAt:  time.Now(),
+		Stack:     string(debug.Stack()),
+	})
+	if err != nil {
+		return
+	}
+
+	conn := wp.pool.Get()
+	defer conn.Close()
+
+	conn.Do("RPUSH", hashTagKey(queue, deadLetterSuffix), payload)
+}
+
+// ReplayDead moves up to n jobs off queue's dead-letter list back onto
+// queue for another attempt, resetting their attempt counter.
+func (b *Broker) ReplayDead(queue string, n int) (int, error) {
+	conn := b.pool.Get()
+	defer conn.Close()
+
+	key := hashTagKey(queue, deadLetterSuffix)
+	replayed := 0
+
+	for i := 0; i < n; i++ {
+		raw, err := redis.Bytes(conn.Do("LPOP", key))
+		if err != nil {
+			// redis.ErrNil means the dead-letter list is empty; any other
+			// error is treated the same way since there's nothing useful
+			// left to replay.
+			break
+		}
+
+		var entry deadLetterEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			continue
+		}
+
+		entry.Envelope.Attempt = 0
+		payload, err := json.Marshal(entry.Envelope)
+		if err != nil {
+			continue
+		}
+
+		if _, err := conn.Do("LPUSH", queue, payload); err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+
+	return replayed, nil
+}
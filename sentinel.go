@@ -0,0 +1,86 @@
+package goku
+
+import (
+	"errors"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// newSentinelPool returns a redis.Pool that dials whichever address
+// Sentinel currently reports as masterName's master, re-resolving it on
+// every new connection and confirming role=master before handing a
+// pooled connection back out.
+//
+// This talks SENTINEL directly over garyburd/redigo rather than using
+// github.com/FZambia/sentinel, which is built on gomodule/redigo/redis —
+// a distinct Conn type that can't satisfy RedisPool here, the same class
+// of mismatch cluster.go hand-rolls Cluster routing to avoid.
+func newSentinelPool(addrs []string, masterName string, timeout time.Duration) *redis.Pool {
+	return &redis.Pool{
+		MaxIdle:     10,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			masterAddr, err := sentinelMasterAddr(addrs, masterName, timeout)
+			if err != nil {
+				return nil, err
+			}
+			return redis.DialTimeout("tcp", masterAddr, timeout, timeout, timeout)
+		},
+		TestOnBorrow: func(conn redis.Conn, t time.Time) error {
+			return checkRoleMaster(conn)
+		},
+	}
+}
+
+// sentinelMasterAddr asks each of addrs in turn for masterName's current
+// master address, returning the first successful answer.
+func sentinelMasterAddr(addrs []string, masterName string, timeout time.Duration) (string, error) {
+	var lastErr error
+
+	for _, addr := range addrs {
+		conn, err := redis.DialTimeout("tcp", addr, timeout, timeout, timeout)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		reply, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", masterName))
+		conn.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(reply) != 2 {
+			lastErr = errors.New("goku: sentinel returned a malformed master address")
+			continue
+		}
+
+		return reply[0] + ":" + reply[1], nil
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("goku: no sentinel addresses configured")
+	}
+	return "", lastErr
+}
+
+// checkRoleMaster returns an error unless conn's ROLE reply says master,
+// so a pooled connection left open across a Sentinel failover isn't
+// handed back out once it's no longer talking to the master.
+func checkRoleMaster(conn redis.Conn) error {
+	role, err := redis.Values(conn.Do("ROLE"))
+	if err != nil {
+		return err
+	}
+
+	name, err := redis.String(role[0], nil)
+	if err != nil {
+		return err
+	}
+	if name != "master" {
+		return errors.New("goku: sentinel-discovered connection is no longer master")
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package goku
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// JobStatus is a job lifecycle state reported through a StatusHook.
+type JobStatus string
+
+// The lifecycle states a job moves through from the broker's LPUSH to the
+// worker's final outcome.
+const (
+	StatusQueued    JobStatus = "queued"
+	StatusRunning   JobStatus = "running"
+	StatusSucceeded JobStatus = "succeeded"
+	StatusFailed    JobStatus = "failed"
+	StatusTimedOut  JobStatus = "timed_out"
+	StatusRetrying  JobStatus = "retrying"
+)
+
+// StatusEvent describes a single job lifecycle transition.
+type StatusEvent struct {
+	JobID  string    `json:"job_id"`
+	Name   string    `json:"name"`
+	Status JobStatus `json:"status"`
+	Time   time.Time `json:"time"`
+}
+
+// StatusHookFunc is invoked in-process for every StatusEvent.
+type StatusHookFunc func(event StatusEvent)
+
+// StatusHook reports job lifecycle events to an external observer. Set
+// either URL, to have every event POSTed there as JSON, or Func, to
+// receive events via an in-process callback. Setting both is allowed;
+// setting neither disables hook delivery.
+type StatusHook struct {
+	URL  string
+	Func StatusHookFunc
+}
+
+// statusKeyTTL bounds how long a job's last known status lingers in Redis
+// after being written.
+const statusKeyTTL = 24 * time.Hour
+
+const statusHookMaxAttempts = 3
+
+func statusKey(jobID string) string {
+	return "goku:job:" + jobID + ":status"
+}
+
+// emitStatus persists event's status in Redis and, if hook is configured,
+// delivers it asynchronously.
+func emitStatus(pool RedisPool, hook StatusHook, jobID, name string, status JobStatus) {
+	if jobID == "" {
+		return
+	}
+
+	event := StatusEvent{JobID: jobID, Name: name, Status: status, Time: time.Now()}
+
+	go persistStatus(pool, event)
+
+	if hook.Func != nil {
+		go hook.Func(event)
+	}
+	if hook.URL != "" {
+		go postStatusHook(hook.URL, event)
+	}
+}
+
+func persistStatus(pool RedisPool, event StatusEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	conn.Do("SET", statusKey(event.JobID), payload, "EX", int(statusKeyTTL.Seconds()))
+}
+
+// postStatusHook POSTs event to url as JSON, retrying with exponential
+// backoff if the request fails.
+func postStatusHook(url string, event StatusEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	delay := 500 * time.Millisecond
+	for attempt := 0; attempt < statusHookMaxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		resp, err := http.Post(url, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 500 {
+			return
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package goku
+
+import (
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// uniqueKeyPrefix namespaces the locks RunUnique uses to deduplicate jobs.
+const uniqueKeyPrefix = "goku:unique:"
+
+// ErrDuplicate is returned by RunUnique when a job with the same
+// UniqueKey is already enqueued and hasn't completed or expired yet.
+var ErrDuplicate = errors.New("goku: job with this unique key is already queued")
+
+// UniqueJob lets a Job opt into deduplication: RunUnique enqueues it only
+// if no job with the same UniqueKey is already queued.
+type UniqueJob interface {
+	Job
+
+	// UniqueKey identifies this job for deduplication purposes.
+	UniqueKey() string
+
+	// UniqueTTL bounds how long the deduplication lock is held if the job
+	// never completes, so a crashed worker can't wedge it forever.
+	UniqueTTL() time.Duration
+}
+
+// RunUnique enqueues job onto the broker's DefaultQueue unless a job with
+// the same UniqueKey is already queued, in which case it returns
+// ErrDuplicate. The deduplication lock is released once the job completes
+// successfully, or after UniqueTTL elapses, whichever comes first.
+func (b *Broker) RunUnique(job UniqueJob) error {
+	if reflect.ValueOf(job).Kind() == reflect.Ptr {
+		return ErrPointer
+	}
+
+	conn := b.pool.Get()
+	defer conn.Close()
+
+	key := job.UniqueKey()
+	reply, err := redis.String(conn.Do("SET", uniqueKeyPrefix+key, "1", "NX", "PX", job.UniqueTTL().Milliseconds()))
+	if err == redis.ErrNil {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	if reply != "OK" {
+		return ErrDuplicate
+	}
+
+	payload, id, err := marshalJob(job, key, b.codec)
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.Do("LPUSH", b.config.DefaultQueue, payload); err != nil {
+		return err
+	}
+
+	emitStatus(b.pool, b.config.StatusHook, id, job.Name(), StatusQueued)
+	return nil
+}
+
+// releaseUniqueLock clears the deduplication lock for key, allowing a new
+// job with the same UniqueKey to be enqueued immediately.
+func releaseUniqueLock(pool RedisPool, key string) {
+	conn := pool.Get()
+	defer conn.Close()
+
+	conn.Do("DEL", uniqueKeyPrefix+key)
+}
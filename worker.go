@@ -0,0 +1,293 @@
+package goku
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// FailureFunc is invoked whenever a job's Execute method returns an error,
+// or times out (then with errJobTimedOut if Execute itself returned nil).
+type FailureFunc func(job Job, err error)
+
+// WorkerConfig configures a WorkerPool.
+type WorkerConfig struct {
+	// NumWorkers is the number of goroutines concurrently pulling jobs off Queues.
+	NumWorkers int
+
+	// Queues lists the Redis queues workers pull jobs from, in priority
+	// order. The pool promotes its own due retries (see RetryPolicy) for
+	// every queue here, independent of whether any Broker is running
+	// against it.
+	Queues []string
+
+	// Hostport is the address of a single Redis instance backing the
+	// queues. Ignored if SentinelAddrs, ClusterAddrs, or Pool is set.
+	Hostport string
+
+	// SentinelAddrs and MasterName, if set, connect the pool through Redis
+	// Sentinel instead of a fixed Hostport.
+	SentinelAddrs []string
+	MasterName    string
+
+	// ClusterAddrs, if set, connects the pool to a Redis Cluster seeded
+	// from these addresses instead of a single instance.
+	ClusterAddrs []string
+
+	// Pool, if set, overrides goku's own pool construction from the
+	// fields above entirely.
+	Pool RedisPool
+
+	// Timeout bounds how long Redis operations are allowed to take.
+	Timeout time.Duration
+
+	// jobTimeout bounds how long a single job's Execute call is given to
+	// run before its TimeoutChan is closed. Zero means no timeout.
+	jobTimeout time.Duration
+
+	// Retry is the default retry policy applied to a failed or timed-out
+	// job, unless it implements RetryableJob.
+	Retry RetryPolicy
+
+	// ReliableFetch, if true, has workers pop jobs into a per-worker
+	// in-flight list instead of popping them outright, so a background
+	// reaper can requeue them if the worker crashes before finishing.
+	ReliableFetch bool
+}
+
+func (c WorkerConfig) redisConfig() redisConfig {
+	return redisConfig{
+		Hostport:      c.Hostport,
+		SentinelAddrs: c.SentinelAddrs,
+		MasterName:    c.MasterName,
+		ClusterAddrs:  c.ClusterAddrs,
+		Pool:          c.Pool,
+	}
+}
+
+// WorkerPoolOptions carries the job registry and failure callback for a WorkerPool.
+type WorkerPoolOptions struct {
+	// Failure, if non-nil, is called whenever a job's Execute returns an
+	// error or the job times out.
+	Failure FailureFunc
+
+	// Jobs lists every Job implementation the pool knows how to run,
+	// registered internally by Name().
+	Jobs []Job
+
+	// StatusHook, if set, is notified of job lifecycle transitions
+	// (running, succeeded, failed, timed_out, retrying, and queued when
+	// the pool promotes a due retry).
+	StatusHook StatusHook
+}
+
+// WorkerPool pulls jobs off one or more Redis queues and executes them.
+type WorkerPool struct {
+	config WorkerConfig
+	opts   WorkerPoolOptions
+	pool   RedisPool
+	jobs   map[string]Job
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewWorkerPool creates a WorkerPool from config, validating required fields.
+func NewWorkerPool(config WorkerConfig, opts WorkerPoolOptions) (*WorkerPool, error) {
+	if len(config.Queues) == 0 {
+		return nil, errNoQueues
+	}
+	if config.NumWorkers <= 0 {
+		return nil, errNoWorkers
+	}
+
+	pool, err := newPool(config.redisConfig(), config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make(map[string]Job, len(opts.Jobs))
+	for _, job := range opts.Jobs {
+		jobs[job.Name()] = job
+	}
+
+	return &WorkerPool{
+		config: config,
+		opts:   opts,
+		pool:   pool,
+		jobs:   jobs,
+		stop:   make(chan struct{}),
+	}, nil
+}
+
+// Start launches the pool's worker goroutines.
+func (wp *WorkerPool) Start() {
+	if wp.config.ReliableFetch {
+		wp.wg.Add(1)
+		go func() {
+			defer wp.wg.Done()
+			wp.runReaper()
+		}()
+	}
+
+	wp.wg.Add(1)
+	go func() {
+		defer wp.wg.Done()
+		wp.runRetryScheduler()
+	}()
+
+	for i := 0; i < wp.config.NumWorkers; i++ {
+		wp.wg.Add(1)
+		go wp.work()
+	}
+}
+
+// Stop signals every worker goroutine to exit and waits for them to finish.
+func (wp *WorkerPool) Stop() {
+	close(wp.stop)
+	wp.wg.Wait()
+}
+
+func (wp *WorkerPool) work() {
+	defer wp.wg.Done()
+
+	workerID := newJobID()
+	if wp.config.ReliableFetch {
+		go wp.runHeartbeat(workerID)
+	}
+
+	for {
+		select {
+		case <-wp.stop:
+			return
+		default:
+		}
+
+		var queue string
+		var payload []byte
+		if wp.config.ReliableFetch {
+			queue, payload = wp.fetchReliable(workerID)
+		} else {
+			queue, payload = wp.fetch()
+		}
+
+		if payload == nil {
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		wp.process(queue, payload)
+
+		if wp.config.ReliableFetch {
+			wp.ackInflight(workerID, queue, payload)
+		}
+	}
+}
+
+func (wp *WorkerPool) fetch() (queue string, payload []byte) {
+	conn := wp.pool.Get()
+	defer conn.Close()
+
+	args := make([]interface{}, 0, len(wp.config.Queues)+1)
+	for _, q := range wp.config.Queues {
+		args = append(args, q)
+	}
+	args = append(args, 1)
+
+	reply, err := redis.Strings(conn.Do("BRPOP", args...))
+	if err != nil || len(reply) != 2 {
+		return "", nil
+	}
+
+	return reply[0], []byte(reply[1])
+}
+
+func (wp *WorkerPool) process(queue string, payload []byte) {
+	var env envelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		return
+	}
+
+	registered, ok := wp.jobs[env.N]
+	if !ok {
+		return
+	}
+
+	codec, err := codecByName(env.Codec)
+	if err != nil {
+		return
+	}
+
+	job, err := hydrateJob(registered, codec, env.Payload)
+	if err != nil {
+		return
+	}
+
+	timeoutChan := make(chan struct{})
+	if wp.config.jobTimeout > 0 {
+		go func() {
+			time.Sleep(wp.config.jobTimeout)
+			close(timeoutChan)
+		}()
+	}
+
+	emitStatus(wp.pool, wp.opts.StatusHook, env.ID, env.N, StatusRunning)
+
+	err = job.Execute(timeoutChan)
+	timedOut := isClosed(timeoutChan)
+
+	status := StatusSucceeded
+	switch {
+	case timedOut:
+		status = StatusTimedOut
+	case err != nil:
+		status = StatusFailed
+	}
+	emitStatus(wp.pool, wp.opts.StatusHook, env.ID, env.N, status)
+
+	if status == StatusSucceeded {
+		if env.Unique != "" {
+			releaseUniqueLock(wp.pool, env.Unique)
+		}
+	} else {
+		if timedOut && err == nil {
+			err = errJobTimedOut
+		}
+		if wp.retryOrDeadLetter(queue, env, err, job) {
+			emitStatus(wp.pool, wp.opts.StatusHook, env.ID, env.N, StatusRetrying)
+		}
+	}
+
+	if err != nil && wp.opts.Failure != nil {
+		wp.opts.Failure(job, err)
+	}
+}
+
+// isClosed reports whether ch has already been closed, without blocking.
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// hydrateJob returns a copy of registered with its fields populated from
+// payload, decoded with codec.
+func hydrateJob(registered Job, codec Codec, payload []byte) (Job, error) {
+	v := reflect.New(reflect.TypeOf(registered))
+	job, ok := v.Interface().(Job)
+	if !ok {
+		return nil, fmt.Errorf("goku: %T is not addressable as a Job", registered)
+	}
+
+	if err := codec.Unmarshal(payload, job); err != nil {
+		return nil, err
+	}
+
+	return v.Elem().Interface().(Job), nil
+}